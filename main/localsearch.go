@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+)
+
+//LocalSearch hybridises ACO with a classical TSP heuristic: it is given
+//the chance to improve an ant's tour right after the ant finishes
+//building it, before that tour is used for pheromone deposit.
+type LocalSearch interface {
+	Apply(ant *ant_t)
+}
+
+//maxLocalSearchIter bounds how many full sweeps a local search may run
+//over a single tour, so a pathological instance can't stall an
+//iteration.
+var maxLocalSearchIter = 100
+
+var localSearchFlag = flag.String("local-search", "none", "local search applied to each ant's tour: none, 2opt, or or-opt")
+
+//selectLocalSearch resolves the --local-search flag into a LocalSearch.
+func selectLocalSearch(name string) LocalSearch {
+	switch name {
+	case "none":
+		return noLocalSearch{}
+	case "2opt":
+		return twoOptLocalSearch{maxIter: maxLocalSearchIter}
+	case "or-opt":
+		return orOptLocalSearch{maxIter: maxLocalSearchIter}
+	default:
+		printError(fmt.Errorf("unknown local search %q (want none, 2opt, or or-opt)", name))
+		return nil
+	}
+}
+
+//noLocalSearch leaves every tour untouched; it is the default so
+//existing behaviour is unchanged unless --local-search is passed.
+type noLocalSearch struct{}
+
+func (noLocalSearch) Apply(ant *ant_t) {}
+
+//twoOptLocalSearch repeatedly looks for a pair of edges whose removal
+//and reconnection shortens the tour, reversing the segment between
+//them, until a full sweep finds no improvement or maxIter is hit.
+type twoOptLocalSearch struct{ maxIter int }
+
+func (ls twoOptLocalSearch) Apply(ant *ant_t) {
+	n := numCities
+	t := ant.tour
+	for iter := 0; iter < ls.maxIter; iter++ {
+		improved := false
+		for i := 0; i+1 < n; i++ {
+			for j := i + 2; j < n; j++ {
+				removed := adjMatrix[t[i]][t[i+1]] + adjMatrix[t[j]][t[(j+1)%n]]
+				added := adjMatrix[t[i]][t[j]] + adjMatrix[t[i+1]][t[(j+1)%n]]
+				if removed > added {
+					reverseSegment(t, i+1, j)
+					ant.tourlength -= removed - added
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+}
+
+func reverseSegment(t []int, i, j int) {
+	for i < j {
+		t[i], t[j] = t[j], t[i]
+		i++
+		j--
+	}
+}
+
+//orOptLocalSearch relocates chains of 1, 2, and 3 consecutive cities to
+//the insertion point elsewhere in the tour that shortens it the most,
+//repeating each chain length until no relocation helps or maxIter is
+//hit.
+type orOptLocalSearch struct{ maxIter int }
+
+func (ls orOptLocalSearch) Apply(ant *ant_t) {
+	for iter := 0; iter < ls.maxIter; iter++ {
+		improved := false
+		for segLen := 1; segLen <= 3 && segLen < numCities; segLen++ {
+			for relocateOneChain(ant, segLen) {
+				improved = true
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+}
+
+//relocateOneChain finds the single best relocation of a segLen-city
+//chain, applies it if it shortens the tour, and reports whether it did.
+func relocateOneChain(ant *ant_t, segLen int) bool {
+	n := numCities
+	t := ant.tour
+	bestDelta := 0.0
+	bestStart, bestPos := -1, -1
+	for start := 0; start < n; start++ {
+		prev := (start - 1 + n) % n
+		end := (start + segLen - 1) % n
+		next := (end + 1) % n
+		if next == start || prev == end {
+			continue //chain covers (almost) the whole tour
+		}
+		removed := adjMatrix[t[prev]][t[start]] + adjMatrix[t[end]][t[next]] - adjMatrix[t[prev]][t[next]]
+		chainStart, chainEnd := t[start], t[end]
+		for pos := 0; pos < n; pos++ {
+			if inChainRange(pos, start, segLen, n) || inChainRange((pos+1)%n, start, segLen, n) || pos == prev {
+				continue
+			}
+			insAfter, insBefore := t[pos], t[(pos+1)%n]
+			added := adjMatrix[insAfter][chainStart] + adjMatrix[chainEnd][insBefore] - adjMatrix[insAfter][insBefore]
+			if delta := added - removed; delta < bestDelta {
+				bestDelta, bestStart, bestPos = delta, start, pos
+			}
+		}
+	}
+	if bestStart == -1 {
+		return false
+	}
+	applyRelocate(t, bestStart, segLen, bestPos)
+	ant.tourlength += bestDelta
+	return true
+}
+
+func inChainRange(pos, start, segLen, n int) bool {
+	for k := 0; k < segLen; k++ {
+		if pos == (start+k)%n {
+			return true
+		}
+	}
+	return false
+}
+
+//applyRelocate removes the segLen-city chain starting at start and
+//reinserts it immediately after the city at pos (both indices into the
+//pre-removal tour), rebuilding the tour slice in place.
+func applyRelocate(t []int, start, segLen, pos int) {
+	n := len(t)
+	chain := make([]int, segLen)
+	for k := 0; k < segLen; k++ {
+		chain[k] = t[(start+k)%n]
+	}
+	inChain := make(map[int]bool, segLen)
+	for _, c := range chain {
+		inChain[c] = true
+	}
+	anchor := t[pos]
+	newTour := make([]int, 0, n)
+	for _, city := range t {
+		if inChain[city] {
+			continue
+		}
+		newTour = append(newTour, city)
+		if city == anchor {
+			newTour = append(newTour, chain...)
+		}
+	}
+	copy(t, newTour)
+}
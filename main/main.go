@@ -15,18 +15,13 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
-	"strconv"
-	"strings"
-
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/plotutil"
-	"gonum.org/v1/plot/vg"
+	"runtime"
+	"sync"
 )
 
 //ant implementation
@@ -37,6 +32,7 @@ type ant_t struct {
 	tour        []int
 	tourIndex   int
 	tourlength  float64
+	rng         *rand.Rand //per-ant RNG, seeded deterministically by --seed
 }
 
 //city implementation
@@ -50,7 +46,7 @@ var cities []city
 var tauMatrix [][]float64
 var adjMatrix [][]float64
 var besttour []int
-var currentIndex int
+var bestLen float64
 var numCities = 0
 var rho = 0.6
 var qval = 1.0
@@ -60,6 +56,31 @@ var numAnts = 10
 var times = 10
 var tours = 500
 
+//ACS-only parameters: q0 controls how often an ant exploits (argmax)
+//rather than explores (roulette wheel), xi is the local pheromone decay,
+//and tau0 is the baseline pheromone local updates relax towards
+//(acsStrategy.InitialTau replaces its default once the graph is loaded).
+var q0 = 0.9
+var xi = 0.1
+var tau0 = 1.0
+
+//strategy is the active pheromone update rule, chosen at startup by
+//the --variant flag.
+var strategy PheromoneStrategy
+
+//localSearch is the tour-improvement pass run on every ant after it
+//finishes its tour, chosen at startup by the --local-search flag.
+var localSearch LocalSearch
+
+var variantFlag = flag.String("variant", "as", "pheromone update strategy: as (classic Ant System), mmas (MAX-MIN Ant System), acs (Ant Colony System), elitist (AS + extra best-tour deposit), or rank (rank-weighted AS)")
+var seedFlag = flag.Int64("seed", 1, "base seed each ant's RNG is deterministically derived from")
+
+var monitorFlag = flag.String("monitor", "plot", "comma-separated iteration monitors: plot, csv, stagnation, or none (e.g. \"plot,csv\")")
+var plotPathFlag = flag.String("monitor-plot", "tsp.png", "output path for the plot monitor's convergence chart")
+var heatmapPathFlag = flag.String("monitor-heatmap", "tau.png", "output path for the plot monitor's pheromone heatmap")
+var csvPathFlag = flag.String("monitor-csv", "aco.csv", "output path for the csv monitor")
+var stagnationWindowFlag = flag.Int("stagnation-window", 50, "iterations without a new best tour before the stagnation monitor reinitialises pheromone")
+
 //prints error and exits on abnormal conditions
 func printError(err error) {
 	fmt.Print(err)
@@ -67,7 +88,15 @@ func printError(err error) {
 }
 
 func main() {
-	initGraph("TSP")
+	flag.Parse()
+	strategy = selectStrategy(*variantFlag)
+	localSearch = selectLocalSearch(*localSearchFlag)
+	monitor := selectMonitor(*monitorFlag)
+	elitistWeight = *elitistWeightFlag
+	rankWeight = *rankWeightFlag
+
+	problem := initGraph("TSP")
+	monitor.OnStart(problem)
 
 	avgavg := make([]float64, times)
 	avgbest := make([]float64, times)
@@ -79,22 +108,28 @@ func main() {
 	initTrail()
 	for i < times {
 		iterations = 0
-		besttour = nil
 		for iterations < tours {
-			//initializes each ant
-			initAnts()
+			absIter := i*tours + iterations
+			//initializes each ant, seeding its RNG from --seed and the
+			//absolute iteration number so runs are reproducible
+			initAnts(absIter)
 			//tour for each ant
 			moveAnts()
-			//intensify pheromone levels
-			intensifyTrail()
-			//compute best and avg tour length for every tour in
-			//500 and add them for average
-			besttourlens[i] += calculateBest()
+			//currentBest is this iteration's own best tour length, which
+			//is what the accumulators and monitor below track (so "Best
+			//So Far" stays a true per-iteration series). calculateBest
+			//also updates the persistent global besttour/bestLen, which
+			//outlive this block, whenever currentBest is a new record;
+			//intensifyTrail and the final Result deposit/report on that
+			//global best instead of currentBest.
+			currentBest := calculateBest()
 			avgtourlens[i] += calculateAvg()
+			besttourlens[i] += currentBest
+			evaporatePheromone()
+			intensifyTrail(bestLen)
+			monitor.OnIteration(absIter, ants, tauMatrix, besttour, currentBest)
 			iterations++
 		}
-		//evaporate pheromone to obtain better results
-		evaporatePheromone()
 		fmt.Println("Iteration: ", i)
 		fmt.Println("Optimal Path: ", besttour)
 		avgavg[i] = avgtourlens[i] / float64(tours)
@@ -104,37 +139,12 @@ func main() {
 	fmt.Println("Average Average:", avgavg)
 	fmt.Println("Average Best:", avgbest)
 
-	p, err := plot.New()
-	if err != nil {
-		printError(err)
-	}
-
-	p.Title.Text = "TSP"
-	p.X.Label.Text = "X"
-	p.Y.Label.Text = "Y"
-
-	avgpts := make(plotter.XYs, times)
-	for i := range avgpts {
-		avgpts[i].Y = avgavg[i]
-		avgpts[i].X = float64(i)
-	}
-
-	bestpts := make(plotter.XYs, times)
-	for i := range bestpts {
-		bestpts[i].Y = avgbest[i]
-		bestpts[i].X = float64(i)
-	}
-	err = plotutil.AddLinePoints(p,
-		"Average So Far", avgpts,
-		"Best So Far", bestpts)
-	if err != nil {
-		printError(err)
-	}
-
-	// Save the plot to a PNG file.
-	if err := p.Save(4*vg.Inch, 4*vg.Inch, "tsp.png"); err != nil {
-		printError(err)
-	}
+	monitor.OnFinish(Result{
+		AvgAvg:   avgavg,
+		AvgBest:  avgbest,
+		BestTour: besttour,
+		BestLen:  bestLen,
+	})
 }
 
 //calculate average tour length of all length for one tour
@@ -148,22 +158,33 @@ func calculateAvg() float64 {
 	return avglength
 }
 
-//calculate best tour length of all length for one tour
+//calculateBest finds this iteration's best-performing ant and returns
+//its tour length. It also updates the persistent global besttour/
+//bestLen, which are never reset once initialized, whenever this
+//iteration's best is a new record - so elitist and rank-based
+//reinforcement, and the final Result, always deposit/report on a true
+//global best rather than just the current iteration's. The returned
+//value is deliberately the iteration's own best, not the (monotonically
+//non-increasing) global one, so callers that average it across an outer
+//block get a meaningful per-iteration series.
 func calculateBest() float64 {
-	var bestlength float64
-	bestlength = ants[0].tourlength
-	if besttour == nil {
-		besttour = make([]int, numCities)
-		copy(besttour, ants[0].tour)
-	}
+	iterBest := ants[0].tourlength
+	iterBestTour := ants[0].tour
 	for i := range ants {
-		if ants[i].tourlength < bestlength {
-
-			bestlength = ants[i].tourlength
-			copy(besttour, ants[i].tour)
+		if ants[i].tourlength < iterBest {
+			iterBest = ants[i].tourlength
+			iterBestTour = ants[i].tour
 		}
 	}
-	return bestlength
+	if besttour == nil {
+		besttour = make([]int, numCities)
+		copy(besttour, iterBestTour)
+		bestLen = iterBest
+	} else if iterBest < bestLen {
+		bestLen = iterBest
+		copy(besttour, iterBestTour)
+	}
+	return iterBest
 }
 
 //initialize pheromone levels
@@ -171,23 +192,18 @@ func initTrail() {
 	tauMatrix = make([][]float64, numCities)
 	for i := range tauMatrix {
 		tauMatrix[i] = make([]float64, numCities)
-		for j := range tauMatrix[i] {
-			if i != j {
-				tauMatrix[i][j] = 1.0 //initialize to base pheromone = 1
-			} else {
-				tauMatrix[i][j] = 0.0
-			}
-		}
 	}
+	reinitTrail(tauMatrix)
 }
 
 //initialize ants
-func initAnts() {
+func initAnts(iter int) {
 	ants = nil
 	ants = make([]ant_t, numAnts)
 	for i := range ants {
+		ants[i].rng = rand.New(rand.NewSource(*seedFlag ^ int64(i)*1000 + int64(iter)))
 		ants[i].tabulist = make([]int, numCities)
-		ants[i].currentCity = rand.Intn(numCities) //randomly assigns ant a city to s
+		ants[i].currentCity = ants[i].rng.Intn(numCities) //randomly assigns ant a city to s
 		ants[i].nextCity = 0
 		ants[i].tour = make([]int, numCities)
 		ants[i].tourIndex = 0
@@ -195,23 +211,77 @@ func initAnts() {
 	}
 }
 
-//move all ants to visit the whole graph
+//move all ants to visit the whole graph, dispatching tour construction
+//across a worker pool so ants build their tours concurrently. The
+//pheromone matrix is only read during construction UNLESS the strategy
+//also does local updates (ACS), in which case goToNewCity writes tau as
+//it goes and ants must be built one at a time instead.
 func moveAnts() {
-	for i := range ants {
-		currentIndex = 0
-		for currentIndex < numCities {
-			goToNewCity(&ants[i])
-			currentIndex++
+	if _, ok := strategy.(localUpdater); ok {
+		for i := range ants {
+			buildTour(&ants[i])
 		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+	for i := range ants {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func(ant *ant_t) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			buildTour(ant)
+		}(&ants[i])
+	}
+	wg.Wait()
+}
+
+//buildTour drives an ant through goToNewCity until it has visited every
+//city, then hands the finished tour to the local search pass.
+func buildTour(ant *ant_t) {
+	for c := 0; c < numCities; c++ {
+		goToNewCity(ant)
 	}
+	//improve the completed tour before it is used for pheromone deposit
+	localSearch.Apply(ant)
 }
 
 //choosing next city
 func goToNewCity(ant *ant_t) {
-	var from, to int
+	from := ant.currentCity
+	to := chooseNextCity(ant, from)
+	ant.nextCity = to
+	ant.tabulist[ant.nextCity] = 1
+	ant.tour[ant.tourIndex] = ant.nextCity
+	ant.tourIndex++
+	ant.tourlength += adjMatrix[ant.currentCity][ant.nextCity]
+	if ant.tourIndex == numCities {
+		ant.tourlength += adjMatrix[ant.tour[numCities-1]][ant.tour[0]]
+	}
+	if lu, ok := strategy.(localUpdater); ok {
+		lu.LocalUpdate(tauMatrix, ant.currentCity, ant.nextCity)
+	}
+	ant.currentCity = ant.nextCity
+}
+
+//chooseNextCity picks the city an ant moves to next. ACS exploits the
+//best-looking edge with probability q0 and otherwise falls back, like
+//every other strategy, to the roulette wheel below.
+func chooseNextCity(ant *ant_t, from int) int {
+	if acs, ok := strategy.(acsStrategy); ok && ant.rng.Float64() < q0 {
+		return acs.argMax(ant, from)
+	}
+	return rouletteWheel(ant, from)
+}
+
+//rouletteWheel samples the next city proportionally to
+//tau^alpha * eta^beta among unvisited cities.
+func rouletteWheel(ant *ant_t, from int) int {
+	var to int
 	var p float64
 	denom := 0.0
-	from = ant.currentCity
 	for to = 0; to < numCities; to++ {
 		if from != to {
 			if ant.tabulist[to] == 0 && tauMatrix[from][to] != 0 && adjMatrix[from][to] != 0 {
@@ -228,7 +298,7 @@ func goToNewCity(ant *ant_t) {
 			if ant.tabulist[to] == 0 {
 				p = (math.Pow(tauMatrix[from][to], alpha) * math.Pow((1.0/adjMatrix[from][to]), beta)) / denom
 
-				if rand.Float64() < p {
+				if ant.rng.Float64() < p {
 					break
 				}
 			}
@@ -238,131 +308,26 @@ func goToNewCity(ant *ant_t) {
 		}
 		to = ((to + 1) % numCities)
 	}
-	ant.nextCity = to
-	ant.tabulist[ant.nextCity] = 1
-	ant.tour[ant.tourIndex] = ant.nextCity
-	ant.tourIndex++
-	ant.tourlength += adjMatrix[ant.currentCity][ant.nextCity]
-	if ant.tourIndex == numCities {
-		ant.tourlength += adjMatrix[ant.tour[numCities-1]][ant.tour[0]]
-	}
-	ant.currentCity = ant.nextCity
-}
-
-//reads from file and creates a list of all the cities coordinates
-func readFile(name string) []city {
-	var dim, i int
-	var cities []city
-	i, dim = 1, 0
-	var startFlag bool
-	startFlag = false
-	if file, err := os.Open(name); err == nil {
-		// make sure it gets closed
-		defer file.Close()
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			str := scanner.Text()
-			if strings.Contains(str, "DIMENSION") {
-				dim = getDim(str)
-				break
-			}
-		}
-		cities = make([]city, dim)
-		for scanner.Scan() {
-			str := scanner.Text()
-			if strings.Contains(str, "EOF") {
-				break
-			} else if startFlag {
-				x, y := tokenize(str)
-				if i <= dim {
-					cities[i-1] = city{x, y}
-					i++
-				} else {
-					startFlag = false
-				}
-			} else if strings.Contains(str, "NODE_COORD_SECTION") {
-				startFlag = true
-			}
-		}
-		// check for errors
-		if err = scanner.Err(); err != nil {
-			printError(err)
-		}
-	} else {
-		printError(err)
-	}
-	return cities
+	return to
 }
 
 //evaporating pheromone after each iteration of the algorithm
 func evaporatePheromone() {
-	var from, to int
-	for from = 0; from < numCities; from++ {
-		for to = 0; to < numCities; to++ {
-			tauMatrix[from][to] = tauMatrix[from][to] * (1.0 - rho)
-			if tauMatrix[from][to] < 0.0 {
-				tauMatrix[from][to] = 1.0
-			}
-		}
-	}
-}
-
-//intensifying pheromone levels
-func intensifyTrail() {
-	var from, to, i, c int
-	for i = 0; i < numAnts; i++ {
-		for c = 0; c < numCities; c++ {
-			from = ants[i].tour[c]
-			to = ants[i].tour[((c + 1) % numCities)]
-			deltatau := (qval / ants[i].tourlength)
-			tauMatrix[from][to] = tauMatrix[from][to] + deltatau
-			tauMatrix[to][from] = tauMatrix[from][to]
-		}
-	}
-}
-
-//making graph
-func initGraph(name string) {
-	cities = readFile(name)
-	numCities = len(cities)
-	adjMatrix = make([][]float64, numCities)
-	for i := range adjMatrix {
-		adjMatrix[i] = make([]float64, numCities)
-		for j := range adjMatrix[i] {
-			adjMatrix[i][j] = calEdge(cities[i], cities[j])
-		}
-	}
+	strategy.Evaporate(tauMatrix)
 }
 
-//calculates edge weight (euclidiean distance)
-func calEdge(c1, c2 city) float64 {
-	return math.Pow((math.Pow((c2.y-c1.y), 2) + math.Pow((c2.y-c1.y), 2)), 0.5)
+//intensifying pheromone levels using the current best tour of bestLen
+func intensifyTrail(bestLen float64) {
+	strategy.Deposit(tauMatrix, ants, besttour, bestLen)
 }
 
-//tokenizes and converts to float
-func tokenize(str string) (x, y float64) {
-	s := strings.Split(str, " ")
-	strX, strY := s[1], s[2]
-	x, err := strconv.ParseFloat(strX, 64) //converts string to float64
-	if err != nil {
-		printError(err)
-	}
-	y, err = strconv.ParseFloat(strY, 64) //converts string to float64
-	if err != nil {
-		printError(err)
-	}
-	return x, y
-}
-
-//gets number of cities from the file
-func getDim(str string) (dim int) {
-	s := strings.Split(str, ":")
-	num := strings.TrimLeft(s[1], " ")
-	if dim, err := strconv.Atoi(num); err == nil {
-		return dim
-	} else {
-		fmt.Print(err)
-		os.Exit(2)
-	}
-	return 0
+//making graph: parses name as a TSPLIB file and dispatches on its
+//EDGE_WEIGHT_TYPE to build the adjacency matrix; see problem.go.
+func initGraph(name string) *Problem {
+	problem := readProblem(name)
+	problem.buildAdjMatrix()
+	cities = problem.cities
+	adjMatrix = problem.adjMatrix
+	numCities = problem.dimension
+	return problem
 }
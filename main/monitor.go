@@ -0,0 +1,327 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+//Result summarises a finished run for Monitor.OnFinish.
+type Result struct {
+	AvgAvg   []float64 //average tour length per outer block
+	AvgBest  []float64 //average best tour length per outer block
+	BestTour []int     //best tour found across the whole run
+	BestLen  float64   //length of BestTour
+}
+
+//Monitor is notified as the algorithm runs, decoupling observability
+//(logging, plotting, convergence tracking) from moveAnts/intensifyTrail.
+type Monitor interface {
+	OnStart(problem *Problem)
+	OnIteration(iter int, ants []ant_t, tau [][]float64, best []int, bestLen float64)
+	OnFinish(result Result)
+}
+
+//selectMonitor resolves the --monitor flag (a comma-separated list) into
+//a single Monitor, composing multiple with MultiMonitor.
+func selectMonitor(spec string) Monitor {
+	var monitors []Monitor
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.TrimSpace(name) {
+		case "", "none":
+			continue
+		case "plot":
+			monitors = append(monitors, NewPlotMonitor(*plotPathFlag, *heatmapPathFlag))
+		case "csv":
+			monitors = append(monitors, NewCSVMonitor(*csvPathFlag))
+		case "stagnation":
+			monitors = append(monitors, NewStagnationMonitor(*stagnationWindowFlag))
+		default:
+			printError(fmt.Errorf("unknown monitor %q (want plot, csv, or stagnation)", name))
+		}
+	}
+	switch len(monitors) {
+	case 0:
+		return NullMonitor{}
+	case 1:
+		return monitors[0]
+	default:
+		return MultiMonitor{monitors: monitors}
+	}
+}
+
+//NullMonitor observes nothing; it's what --monitor=none resolves to.
+type NullMonitor struct{}
+
+func (NullMonitor) OnStart(*Problem)                                      {}
+func (NullMonitor) OnIteration(int, []ant_t, [][]float64, []int, float64) {}
+func (NullMonitor) OnFinish(Result)                                       {}
+
+//MultiMonitor fans every call out to a list of monitors, so e.g. csv
+//logging and the stagnation restart can run side by side.
+type MultiMonitor struct{ monitors []Monitor }
+
+func (m MultiMonitor) OnStart(problem *Problem) {
+	for _, mon := range m.monitors {
+		mon.OnStart(problem)
+	}
+}
+
+func (m MultiMonitor) OnIteration(iter int, ants []ant_t, tau [][]float64, best []int, bestLen float64) {
+	for _, mon := range m.monitors {
+		mon.OnIteration(iter, ants, tau, best, bestLen)
+	}
+}
+
+func (m MultiMonitor) OnFinish(result Result) {
+	for _, mon := range m.monitors {
+		mon.OnFinish(result)
+	}
+}
+
+//CSVMonitor streams the best/avg/worst tour length and the pheromone
+//entropy of every iteration to a CSV file.
+type CSVMonitor struct {
+	path   string
+	file   *os.File
+	writer *csv.Writer
+}
+
+func NewCSVMonitor(path string) *CSVMonitor {
+	return &CSVMonitor{path: path}
+}
+
+func (m *CSVMonitor) OnStart(problem *Problem) {
+	f, err := os.Create(m.path)
+	if err != nil {
+		printError(err)
+		return
+	}
+	m.file = f
+	m.writer = csv.NewWriter(f)
+	m.writer.Write([]string{"iteration", "best", "avg", "worst", "entropy"})
+}
+
+func (m *CSVMonitor) OnIteration(iter int, ants []ant_t, tau [][]float64, best []int, bestLen float64) {
+	if m.writer == nil {
+		return
+	}
+	avg, worst := avgAndWorstLength(ants)
+	m.writer.Write([]string{
+		strconv.Itoa(iter),
+		strconv.FormatFloat(bestLen, 'f', -1, 64),
+		strconv.FormatFloat(avg, 'f', -1, 64),
+		strconv.FormatFloat(worst, 'f', -1, 64),
+		strconv.FormatFloat(pheromoneEntropy(tau), 'f', -1, 64),
+	})
+	m.writer.Flush()
+}
+
+func (m *CSVMonitor) OnFinish(result Result) {
+	if m.file != nil {
+		m.file.Close()
+	}
+}
+
+//avgAndWorstLength returns the mean and maximum tour length among ants.
+func avgAndWorstLength(ants []ant_t) (avg, worst float64) {
+	total := 0.0
+	worst = ants[0].tourlength
+	for _, ant := range ants {
+		total += ant.tourlength
+		if ant.tourlength > worst {
+			worst = ant.tourlength
+		}
+	}
+	return total / float64(len(ants)), worst
+}
+
+//pheromoneEntropy returns the pheromone entropy H = -sum(p*log(p))
+//averaged across rows, each row normalised into a probability
+//distribution first.
+func pheromoneEntropy(tau [][]float64) float64 {
+	total := 0.0
+	for _, row := range tau {
+		rowSum := 0.0
+		for _, v := range row {
+			rowSum += v
+		}
+		if rowSum <= 0 {
+			continue
+		}
+		h := 0.0
+		for _, v := range row {
+			if v <= 0 {
+				continue
+			}
+			p := v / rowSum
+			h -= p * math.Log(p)
+		}
+		total += h
+	}
+	return total / float64(len(tau))
+}
+
+//PlotMonitor produces the convergence chart main used to always draw,
+//plus a pheromone heatmap, via gonum.
+type PlotMonitor struct {
+	linePath    string
+	heatmapPath string
+	lastTau     [][]float64
+}
+
+func NewPlotMonitor(linePath, heatmapPath string) *PlotMonitor {
+	return &PlotMonitor{linePath: linePath, heatmapPath: heatmapPath}
+}
+
+func (m *PlotMonitor) OnStart(problem *Problem) {}
+
+func (m *PlotMonitor) OnIteration(iter int, ants []ant_t, tau [][]float64, best []int, bestLen float64) {
+	m.lastTau = tau
+}
+
+func (m *PlotMonitor) OnFinish(result Result) {
+	m.plotConvergence(result)
+	m.plotHeatmap()
+}
+
+func (m *PlotMonitor) plotConvergence(result Result) {
+	p, err := plot.New()
+	if err != nil {
+		printError(err)
+		return
+	}
+
+	p.Title.Text = "TSP"
+	p.X.Label.Text = "X"
+	p.Y.Label.Text = "Y"
+
+	avgpts := make(plotter.XYs, len(result.AvgAvg))
+	for i := range avgpts {
+		avgpts[i].Y = result.AvgAvg[i]
+		avgpts[i].X = float64(i)
+	}
+
+	bestpts := make(plotter.XYs, len(result.AvgBest))
+	for i := range bestpts {
+		bestpts[i].Y = result.AvgBest[i]
+		bestpts[i].X = float64(i)
+	}
+	if err := plotutil.AddLinePoints(p,
+		"Average So Far", avgpts,
+		"Best So Far", bestpts); err != nil {
+		printError(err)
+		return
+	}
+
+	if err := p.Save(4*vg.Inch, 4*vg.Inch, m.linePath); err != nil {
+		printError(err)
+	}
+}
+
+func (m *PlotMonitor) plotHeatmap() {
+	if m.lastTau == nil {
+		return
+	}
+	p, err := plot.New()
+	if err != nil {
+		printError(err)
+		return
+	}
+	p.Title.Text = "Pheromone Levels"
+
+	cm := moreland.SmoothBlueRed()
+	lo, hi := tauRange(m.lastTau)
+	cm.SetMin(lo)
+	cm.SetMax(hi)
+
+	heatmap := plotter.NewHeatMap(tauGrid{m.lastTau}, cm.Palette(256))
+	p.Add(heatmap)
+
+	if err := p.Save(4*vg.Inch, 4*vg.Inch, m.heatmapPath); err != nil {
+		printError(err)
+	}
+}
+
+//tauRange returns the min and max pheromone level in tau, needed to
+//bound the heatmap's color scale before deriving a palette from it.
+func tauRange(tau [][]float64) (lo, hi float64) {
+	lo, hi = tau[0][0], tau[0][0]
+	for _, row := range tau {
+		for _, v := range row {
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+	}
+	return lo, hi
+}
+
+//tauGrid adapts a pheromone matrix to gonum's plotter.GridXYZ.
+type tauGrid struct{ tau [][]float64 }
+
+func (g tauGrid) Dims() (c, r int)   { return len(g.tau), len(g.tau) }
+func (g tauGrid) X(c int) float64    { return float64(c) }
+func (g tauGrid) Y(r int) float64    { return float64(r) }
+func (g tauGrid) Z(c, r int) float64 { return g.tau[r][c] }
+
+//StagnationMonitor reinitialises pheromone once the best tour hasn't
+//improved for window iterations, helping escape local optima.
+type StagnationMonitor struct {
+	window           int
+	bestSeen         float64
+	haveBest         bool
+	sinceImprovement int
+}
+
+func NewStagnationMonitor(window int) *StagnationMonitor {
+	return &StagnationMonitor{window: window}
+}
+
+func (m *StagnationMonitor) OnStart(problem *Problem) {
+	m.haveBest = false
+	m.sinceImprovement = 0
+}
+
+func (m *StagnationMonitor) OnIteration(iter int, ants []ant_t, tau [][]float64, best []int, bestLen float64) {
+	if !m.haveBest || bestLen < m.bestSeen {
+		m.bestSeen = bestLen
+		m.haveBest = true
+		m.sinceImprovement = 0
+		return
+	}
+	m.sinceImprovement++
+	if m.sinceImprovement >= m.window {
+		reinitTrail(tau)
+		m.sinceImprovement = 0
+	}
+}
+
+func (m *StagnationMonitor) OnFinish(result Result) {}
+
+//reinitTrail resets every edge of tau back to the active strategy's
+//base pheromone level.
+func reinitTrail(tau [][]float64) {
+	base := strategy.InitialTau()
+	for i := range tau {
+		for j := range tau[i] {
+			if i != j {
+				tau[i][j] = base
+			} else {
+				tau[i][j] = 0.0
+			}
+		}
+	}
+}
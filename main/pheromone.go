@@ -0,0 +1,286 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"sort"
+)
+
+//PheromoneStrategy decouples the pheromone update rule from moveAnts/
+//intensifyTrail so the three canonical ACO variants can be swapped in
+//via a CLI flag instead of being hard-coded.
+type PheromoneStrategy interface {
+	//InitialTau returns the pheromone level every edge is seeded with.
+	InitialTau() float64
+	//Evaporate decays tau in place.
+	Evaporate(tau [][]float64)
+	//Deposit reinforces tau using the ants that just finished a tour and
+	//the best tour (and its length) found so far across the whole run.
+	Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64)
+}
+
+//localUpdater is implemented by strategies (currently only ACS) that
+//need to touch pheromone on an edge the moment an ant crosses it,
+//rather than waiting for the end-of-iteration Deposit.
+type localUpdater interface {
+	LocalUpdate(tau [][]float64, from, to int)
+}
+
+//selectStrategy resolves the --variant flag into a PheromoneStrategy.
+func selectStrategy(name string) PheromoneStrategy {
+	switch name {
+	case "as":
+		return classicStrategy{}
+	case "mmas":
+		return mmasStrategy{}
+	case "acs":
+		return acsStrategy{}
+	case "elitist":
+		return elitistStrategy{}
+	case "rank":
+		return rankBasedStrategy{}
+	default:
+		printError(fmt.Errorf("unknown pheromone strategy %q (want as, mmas, acs, elitist, or rank)", name))
+		return nil
+	}
+}
+
+//classicStrategy is the original Ant System update: every ant deposits
+//qval/tourlength on every edge it visited, and evaporation decays every
+//edge uniformly.
+type classicStrategy struct{}
+
+func (classicStrategy) InitialTau() float64 { return 1.0 }
+
+func (classicStrategy) Evaporate(tau [][]float64) {
+	for from := range tau {
+		for to := range tau[from] {
+			tau[from][to] *= 1.0 - rho
+			if tau[from][to] < 0.0 {
+				tau[from][to] = 1.0
+			}
+		}
+	}
+}
+
+func (classicStrategy) Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64) {
+	for i := range ants {
+		for c := 0; c < numCities; c++ {
+			from := ants[i].tour[c]
+			to := ants[i].tour[(c+1)%numCities]
+			deltatau := qval / ants[i].tourlength
+			tau[from][to] += deltatau
+			tau[to][from] = tau[from][to]
+		}
+	}
+}
+
+//mmasP follows Stutzle & Hoos' original MAX-MIN Ant System
+//parameterisation: p is the probability of the best ant's tour being
+//reconstructed once convergence is reached. clamp pairs it with avg = n/2,
+//which approximates the number of branches any one node offers.
+const mmasP = 0.05
+
+//mmasStrategy implements MAX-MIN Ant System: only the best tour found so
+//far in the run deposits, and every tau is clamped into [tauMin, tauMax]
+//after each update.
+type mmasStrategy struct{}
+
+func (mmasStrategy) InitialTau() float64 {
+	//The real tauMax depends on the best tour length, which isn't known
+	//before the first ant runs; estimate it from a cheap nearest-neighbour
+	//tour instead of guessing, so tau starts at tauMax as specified.
+	return 1.0 / (rho * nearestNeighborTourLength())
+}
+
+//nearestNeighborTourLength builds a greedy nearest-neighbour tour over
+//the current adjMatrix and returns its length, as a cheap stand-in for
+//the optimum when MMAS needs a tauMax estimate before any ant has run.
+func nearestNeighborTourLength() float64 {
+	n := numCities
+	visited := make([]bool, n)
+	visited[0] = true
+	current := 0
+	total := 0.0
+	for i := 1; i < n; i++ {
+		next, bestDist := -1, math.Inf(1)
+		for j := 0; j < n; j++ {
+			if !visited[j] && adjMatrix[current][j] < bestDist {
+				next, bestDist = j, adjMatrix[current][j]
+			}
+		}
+		visited[next] = true
+		total += bestDist
+		current = next
+	}
+	total += adjMatrix[current][0]
+	return total
+}
+
+func (mmasStrategy) Evaporate(tau [][]float64) {
+	for from := range tau {
+		for to := range tau[from] {
+			tau[from][to] *= 1.0 - rho
+		}
+	}
+}
+
+func (s mmasStrategy) Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64) {
+	deltatau := qval / bestLen
+	for c := 0; c < numCities; c++ {
+		from := best[c]
+		to := best[(c+1)%numCities]
+		tau[from][to] += deltatau
+		tau[to][from] = tau[from][to]
+	}
+	s.clamp(tau, bestLen)
+}
+
+func (mmasStrategy) clamp(tau [][]float64, bestLen float64) {
+	n := float64(numCities)
+	tauMax := 1.0 / (rho * bestLen)
+	avg := n / 2.0
+	pn := math.Pow(mmasP, 1.0/n)
+	tauMin := tauMax * (1.0 - pn) / ((avg - 1.0) * pn)
+	for from := range tau {
+		for to := range tau[from] {
+			if from == to {
+				continue
+			}
+			switch {
+			case tau[from][to] > tauMax:
+				tau[from][to] = tauMax
+			case tau[from][to] < tauMin:
+				tau[from][to] = tauMin
+			}
+		}
+	}
+}
+
+//acsStrategy implements Ant Colony System: a global update that only
+//reinforces the best tour (with its own evaporation folded in), plus a
+//local update (LocalUpdate) applied by each ant as it crosses an edge.
+type acsStrategy struct{}
+
+//InitialTau sets tau0 to 1/(n*Lnn), where Lnn is the length of a
+//nearest-neighbour tour, and seeds every edge with it. That keeps tau0
+//below the qval/bestLen level Deposit moves best-tour edges towards.
+func (acsStrategy) InitialTau() float64 {
+	tau0 = 1.0 / (float64(numCities) * nearestNeighborTourLength())
+	return tau0
+}
+
+//Evaporate is a no-op for ACS: evaporation only happens on the best
+//tour's edges, as part of Deposit.
+func (acsStrategy) Evaporate(tau [][]float64) {}
+
+func (acsStrategy) Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64) {
+	deltatau := qval / bestLen
+	for c := 0; c < numCities; c++ {
+		from := best[c]
+		to := best[(c+1)%numCities]
+		tau[from][to] = (1.0-rho)*tau[from][to] + rho*deltatau
+		tau[to][from] = tau[from][to]
+	}
+}
+
+//LocalUpdate implements the ACS local pheromone update
+//tau <- (1-xi)*tau + xi*tau0, applied the instant an ant traverses an
+//edge so later ants in the same iteration see it as slightly less
+//attractive.
+func (acsStrategy) LocalUpdate(tau [][]float64, from, to int) {
+	tau[from][to] = (1.0-xi)*tau[from][to] + xi*tau0
+	tau[to][from] = tau[from][to]
+}
+
+//argMax picks the unvisited city maximising tau^alpha * eta^beta, used
+//by ACS's pseudo-random-proportional rule when it chooses to exploit
+//rather than explore.
+func (acsStrategy) argMax(ant *ant_t, from int) int {
+	best := -1
+	bestScore := 0.0
+	for to := 0; to < numCities; to++ {
+		if to == from || ant.tabulist[to] != 0 || adjMatrix[from][to] == 0 {
+			continue
+		}
+		score := math.Pow(tauMatrix[from][to], alpha) * math.Pow(1.0/adjMatrix[from][to], beta)
+		if best == -1 || score > bestScore {
+			best, bestScore = to, score
+		}
+	}
+	return best
+}
+
+//elitistWeight (e) and rankWeight (w) configure elitistStrategy and
+//rankBasedStrategy below, following the usual recommendation of e
+//around the ant count and w in the 4-10 range. Both are overridable via
+//--elitist-weight/--rank-weight; main() copies the flag values in after
+//flag.Parse().
+var elitistWeight = float64(numAnts)
+var rankWeight = 6
+
+var elitistWeightFlag = flag.Float64("elitist-weight", elitistWeight, "extra deposit weight (e) for the elitist pheromone strategy, recommended around the ant count")
+var rankWeightFlag = flag.Int("rank-weight", rankWeight, "number of top ants (w) that deposit under the rank-based pheromone strategy, recommended 4-10")
+
+//elitistStrategy is classic AS plus an extra deposit of
+//e*qval/bestLen on every edge of the best tour seen so far, so the
+//search is pulled harder towards it.
+type elitistStrategy struct{}
+
+func (elitistStrategy) InitialTau() float64 { return 1.0 }
+
+func (elitistStrategy) Evaporate(tau [][]float64) {
+	classicStrategy{}.Evaporate(tau)
+}
+
+func (elitistStrategy) Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64) {
+	classicStrategy{}.Deposit(tau, ants, best, bestLen)
+	deltatau := elitistWeight * qval / bestLen
+	for c := 0; c < numCities; c++ {
+		from := best[c]
+		to := best[(c+1)%numCities]
+		tau[from][to] += deltatau
+		tau[to][from] = tau[from][to]
+	}
+}
+
+//rankBasedStrategy lets only the top rankWeight-1 ants of the iteration
+//and the best tour seen so far deposit: the ant ranked r contributes
+//(w-r)*qval/Lr, and the best tour contributes w*qval/bestLen.
+type rankBasedStrategy struct{}
+
+func (rankBasedStrategy) InitialTau() float64 { return 1.0 }
+
+func (rankBasedStrategy) Evaporate(tau [][]float64) {
+	classicStrategy{}.Evaporate(tau)
+}
+
+func (rankBasedStrategy) Deposit(tau [][]float64, ants []ant_t, best []int, bestLen float64) {
+	ranked := make([]ant_t, len(ants))
+	copy(ranked, ants)
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].tourlength < ranked[j].tourlength })
+
+	w := rankWeight
+	top := w - 1
+	if top > len(ranked) {
+		top = len(ranked)
+	}
+	for r := 0; r < top; r++ {
+		weight := float64(w-(r+1)) * qval / ranked[r].tourlength
+		for c := 0; c < numCities; c++ {
+			from := ranked[r].tour[c]
+			to := ranked[r].tour[(c+1)%numCities]
+			tau[from][to] += weight
+			tau[to][from] = tau[from][to]
+		}
+	}
+
+	deltatau := float64(w) * qval / bestLen
+	for c := 0; c < numCities; c++ {
+		from := best[c]
+		to := best[(c+1)%numCities]
+		tau[from][to] += deltatau
+		tau[to][from] = tau[from][to]
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//Problem holds everything read out of a TSPLIB file: the raw node
+//coordinates (when given), the resolved edge-weight metric, and the
+//adjacency matrix that metric implies. initGraph publishes the result
+//onto the package-level cities/adjMatrix/numCities the solver uses.
+type Problem struct {
+	dimension        int
+	edgeWeightType   string
+	edgeWeightFormat string
+	cities           []city
+	explicitWeights  []float64 //raw EDGE_WEIGHT_SECTION values, in file order
+	adjMatrix        [][]float64
+}
+
+//readProblem scans name for its header fields and NODE_COORD_SECTION or
+//EDGE_WEIGHT_SECTION body, stopping at EOF. EUC_2D is assumed when no
+//EDGE_WEIGHT_TYPE is present, matching the original implicit behaviour.
+func readProblem(name string) *Problem {
+	p := &Problem{edgeWeightType: "EUC_2D"}
+	file, err := os.Open(name)
+	if err != nil {
+		printError(err)
+		return p
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "EOF"):
+			return p
+		case strings.Contains(line, "DIMENSION"):
+			p.dimension = getDim(line)
+		case strings.Contains(line, "EDGE_WEIGHT_FORMAT"):
+			p.edgeWeightFormat = headerValue(line)
+		case strings.Contains(line, "EDGE_WEIGHT_TYPE"):
+			p.edgeWeightType = headerValue(line)
+		case strings.Contains(line, "NODE_COORD_SECTION"):
+			p.cities = readCities(scanner, p.dimension)
+		case strings.Contains(line, "EDGE_WEIGHT_SECTION"):
+			p.explicitWeights = readWeights(scanner, explicitWeightCount(p.dimension, p.edgeWeightFormat))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		printError(err)
+	}
+	return p
+}
+
+//buildAdjMatrix fills in p.adjMatrix by dispatching on p.edgeWeightType.
+func (p *Problem) buildAdjMatrix() {
+	n := p.dimension
+	p.adjMatrix = make([][]float64, n)
+	for i := range p.adjMatrix {
+		p.adjMatrix[i] = make([]float64, n)
+	}
+	if p.edgeWeightType == "EXPLICIT" {
+		p.fillExplicit()
+		return
+	}
+	dist := metricFunc(p.edgeWeightType)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			p.adjMatrix[i][j] = dist(p.cities[i], p.cities[j])
+		}
+	}
+}
+
+//fillExplicit unpacks p.explicitWeights according to p.edgeWeightFormat
+//into the full symmetric adjacency matrix.
+func (p *Problem) fillExplicit() {
+	n := p.dimension
+	w := p.explicitWeights
+	idx := 0
+	switch p.edgeWeightFormat {
+	case "FULL_MATRIX":
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				p.adjMatrix[i][j] = w[idx]
+				idx++
+			}
+		}
+	case "UPPER_ROW":
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				p.adjMatrix[i][j], p.adjMatrix[j][i] = w[idx], w[idx]
+				idx++
+			}
+		}
+	case "LOWER_DIAG_ROW":
+		for i := 0; i < n; i++ {
+			for j := 0; j <= i; j++ {
+				p.adjMatrix[i][j], p.adjMatrix[j][i] = w[idx], w[idx]
+				idx++
+			}
+		}
+	case "UPPER_DIAG_ROW":
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				p.adjMatrix[i][j], p.adjMatrix[j][i] = w[idx], w[idx]
+				idx++
+			}
+		}
+	default:
+		printError(fmt.Errorf("unsupported EDGE_WEIGHT_FORMAT %q", p.edgeWeightFormat))
+	}
+}
+
+//explicitWeightCount returns how many numbers EDGE_WEIGHT_SECTION holds
+//for the given format, so readWeights knows when to stop.
+func explicitWeightCount(dim int, format string) int {
+	switch format {
+	case "FULL_MATRIX":
+		return dim * dim
+	case "UPPER_ROW", "LOWER_ROW":
+		return dim * (dim - 1) / 2
+	case "UPPER_DIAG_ROW", "LOWER_DIAG_ROW":
+		return dim * (dim + 1) / 2
+	default:
+		printError(fmt.Errorf("unsupported EDGE_WEIGHT_FORMAT %q", format))
+		return 0
+	}
+}
+
+//metricFunc resolves an EDGE_WEIGHT_TYPE into the distance function used
+//to fill in an implicit (non-EXPLICIT) adjacency matrix.
+func metricFunc(name string) func(c1, c2 city) float64 {
+	switch name {
+	case "EUC_2D", "":
+		return calEdgeEUC2D
+	case "CEIL_2D":
+		return calEdgeCEIL2D
+	case "ATT":
+		return calEdgeATT
+	case "GEO":
+		return calEdgeGEO
+	default:
+		printError(fmt.Errorf("unsupported EDGE_WEIGHT_TYPE %q", name))
+		return nil
+	}
+}
+
+//calEdgeEUC2D is the standard TSPLIB Euclidean metric, rounded to the
+//nearest integer per spec.
+func calEdgeEUC2D(c1, c2 city) float64 {
+	dx, dy := c2.x-c1.x, c2.y-c1.y
+	return math.Round(math.Sqrt(dx*dx + dy*dy))
+}
+
+//calEdgeCEIL2D is EUC_2D rounded up instead of to nearest.
+func calEdgeCEIL2D(c1, c2 city) float64 {
+	dx, dy := c2.x-c1.x, c2.y-c1.y
+	return math.Ceil(math.Sqrt(dx*dx + dy*dy))
+}
+
+//calEdgeATT is the pseudo-Euclidean metric used by the att-class
+//TSPLIB instances.
+func calEdgeATT(c1, c2 city) float64 {
+	dx, dy := c2.x-c1.x, c2.y-c1.y
+	return math.Ceil(math.Sqrt((dx*dx + dy*dy) / 10.0))
+}
+
+//earthRadius is the value TSPLIB's GEO metric was defined against.
+const earthRadius = 6378.388
+
+//calEdgeGEO converts DDD.MM latitude/longitude coordinates to radians
+//and applies the TSPLIB great-circle distance formula.
+func calEdgeGEO(c1, c2 city) float64 {
+	lat1, lon1 := toRadians(c1.x), toRadians(c1.y)
+	lat2, lon2 := toRadians(c2.x), toRadians(c2.y)
+	q1 := math.Cos(lon1 - lon2)
+	q2 := math.Cos(lat1 - lat2)
+	q3 := math.Cos(lat1 + lat2)
+	return math.Trunc(earthRadius*math.Acos(0.5*((1.0+q1)*q2-(1.0-q1)*q3)) + 1.0)
+}
+
+//toRadians converts a TSPLIB DDD.MM coordinate (degrees, with minutes
+//packed into the fractional part) to radians.
+func toRadians(coord float64) float64 {
+	deg := math.Trunc(coord)
+	min := coord - deg
+	return math.Pi * (deg + 5.0*min/3.0) / 180.0
+}
+
+//readCities reads dim "index x y" lines from scanner, as found in a
+//NODE_COORD_SECTION.
+func readCities(scanner *bufio.Scanner, dim int) []city {
+	cs := make([]city, dim)
+	for i := 0; i < dim && scanner.Scan(); i++ {
+		x, y := tokenize(scanner.Text())
+		cs[i] = city{x, y}
+	}
+	return cs
+}
+
+//readWeights reads count whitespace-separated numbers from scanner,
+//possibly spread across several lines, as found in an
+//EDGE_WEIGHT_SECTION.
+func readWeights(scanner *bufio.Scanner, count int) []float64 {
+	weights := make([]float64, 0, count)
+	for len(weights) < count && scanner.Scan() {
+		for _, tok := range strings.Fields(scanner.Text()) {
+			v, err := strconv.ParseFloat(tok, 64)
+			if err != nil {
+				printError(err)
+			}
+			weights = append(weights, v)
+		}
+	}
+	return weights
+}
+
+//headerValue returns the part of a "KEY: value" header line after the
+//colon, trimmed of surrounding whitespace.
+func headerValue(line string) string {
+	s := strings.SplitN(line, ":", 2)
+	if len(s) < 2 {
+		return ""
+	}
+	return strings.TrimSpace(s[1])
+}
+
+//tokenizes and converts to float
+func tokenize(str string) (x, y float64) {
+	s := strings.Fields(str)
+	strX, strY := s[1], s[2]
+	x, err := strconv.ParseFloat(strX, 64) //converts string to float64
+	if err != nil {
+		printError(err)
+	}
+	y, err = strconv.ParseFloat(strY, 64) //converts string to float64
+	if err != nil {
+		printError(err)
+	}
+	return x, y
+}
+
+//gets number of cities from the file
+func getDim(str string) (dim int) {
+	s := strings.Split(str, ":")
+	num := strings.TrimLeft(s[1], " ")
+	if dim, err := strconv.Atoi(num); err == nil {
+		return dim
+	} else {
+		fmt.Print(err)
+		os.Exit(2)
+	}
+	return 0
+}